@@ -0,0 +1,134 @@
+// Copyright (c) 2019 Coinbase, Inc. See LICENSE
+
+// Package caip implements minimal validation for the CAIP-2 (chain id) and
+// CAIP-10 (account id) string formats used to describe session namespaces.
+package caip
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	namespacePattern = regexp.MustCompile(`^[-a-zA-Z0-9]{1,16}$`)
+	referencePattern = regexp.MustCompile(`^[-a-zA-Z0-9]{1,32}$`)
+	addressPattern   = regexp.MustCompile(`^[a-zA-Z0-9]{1,64}$`)
+)
+
+// ErrorCode - a machine-readable CAIP validation failure code
+type ErrorCode string
+
+const (
+	// ErrInvalidFormat - the string isn't colon-delimited as CAIP expects
+	ErrInvalidFormat ErrorCode = "caip.invalid_format"
+
+	// ErrInvalidNamespace - the namespace segment failed validation
+	ErrInvalidNamespace ErrorCode = "caip.invalid_namespace"
+
+	// ErrInvalidReference - the reference segment failed validation
+	ErrInvalidReference ErrorCode = "caip.invalid_reference"
+
+	// ErrInvalidAddress - the address segment failed validation
+	ErrInvalidAddress ErrorCode = "caip.invalid_address"
+
+	// ErrNamespaceNotCovered - the accounts do not cover every chain a
+	// guest required
+	ErrNamespaceNotCovered ErrorCode = "caip.namespace_not_covered"
+)
+
+// Error - a CAIP validation error carrying a structured Code in addition to
+// a human-readable message
+type Error struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(code ErrorCode, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Namespace - the chains and methods a guest requires under a single CAIP-2
+// namespace (e.g. "eip155")
+type Namespace struct {
+	Chains  []string
+	Methods []string
+}
+
+// ValidateChainID - validates a CAIP-2 chain id, e.g. "eip155:1"
+func ValidateChainID(chainID string) error {
+	parts := strings.SplitN(chainID, ":", 2)
+	if len(parts) != 2 {
+		return newError(ErrInvalidFormat, fmt.Sprintf("malformed chain id: %s", chainID))
+	}
+	if !namespacePattern.MatchString(parts[0]) {
+		return newError(ErrInvalidNamespace, fmt.Sprintf("invalid namespace: %s", parts[0]))
+	}
+	if !referencePattern.MatchString(parts[1]) {
+		return newError(ErrInvalidReference, fmt.Sprintf("invalid reference: %s", parts[1]))
+	}
+	return nil
+}
+
+// ValidateAccountID - validates a CAIP-10 account id, e.g. "eip155:1:0xabc..."
+func ValidateAccountID(accountID string) error {
+	parts := strings.SplitN(accountID, ":", 3)
+	if len(parts) != 3 {
+		return newError(ErrInvalidFormat, fmt.Sprintf("malformed account id: %s", accountID))
+	}
+	if !namespacePattern.MatchString(parts[0]) {
+		return newError(ErrInvalidNamespace, fmt.Sprintf("invalid namespace: %s", parts[0]))
+	}
+	if !referencePattern.MatchString(parts[1]) {
+		return newError(ErrInvalidReference, fmt.Sprintf("invalid reference: %s", parts[1]))
+	}
+	if !addressPattern.MatchString(parts[2]) {
+		return newError(ErrInvalidAddress, fmt.Sprintf("invalid address: %s", parts[2]))
+	}
+	return nil
+}
+
+// ValidateAccountIDs - validates each of accounts as a CAIP-10 account id
+func ValidateAccountIDs(accounts []string) error {
+	for _, accountID := range accounts {
+		if err := ValidateAccountID(accountID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ChainID - the CAIP-2 chain id (namespace:reference) that accountID belongs to
+func ChainID(accountID string) string {
+	parts := strings.SplitN(accountID, ":", 3)
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0] + ":" + parts[1]
+}
+
+// CoversRequiredNamespaces - whether accounts (CAIP-10) include at least one
+// account on every chain required by namespaces
+func CoversRequiredNamespaces(namespaces map[string]Namespace, accounts []string) error {
+	covered := map[string]bool{}
+	for _, accountID := range accounts {
+		covered[ChainID(accountID)] = true
+	}
+
+	for _, ns := range namespaces {
+		for _, chainID := range ns.Chains {
+			if !covered[chainID] {
+				return newError(
+					ErrNamespaceNotCovered,
+					fmt.Sprintf("no approved account for required chain: %s", chainID),
+				)
+			}
+		}
+	}
+
+	return nil
+}
@@ -3,8 +3,15 @@
 package rpc
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/CoinbaseWallet/walletlinkd/caip"
 	"github.com/CoinbaseWallet/walletlinkd/store"
 	"github.com/CoinbaseWallet/walletlinkd/store/models"
 	"github.com/pkg/errors"
@@ -16,17 +23,88 @@ const (
 
 	// GuestMessageJoinSession - join session
 	GuestMessageJoinSession = "joinSession"
+
+	// HostMessageUpdateSession - update session metadata; controller only
+	HostMessageUpdateSession = "updateSession"
+
+	// HostMessageExtendSession - extend a session's TTL; controller only
+	HostMessageExtendSession = "extendSession"
+
+	// MessagePingSession - keep-alive ping; may be sent by either the host
+	// or the guest
+	MessagePingSession = "pingSession"
+
+	// GuestMessageProposeSession - propose a session, pending host approval
+	GuestMessageProposeSession = "proposeSession"
+
+	// HostMessageApproveSession - approve a pending session proposal
+	HostMessageApproveSession = "approveSession"
+
+	// HostMessageRejectSession - reject a pending session proposal
+	HostMessageRejectSession = "rejectSession"
+
+	// MessagePublish - relay a sequenced payload to the other side of the
+	// session; may be sent by either the host or the guest
+	MessagePublish = "publish"
+
+	// MessageSyncSession - fetch the session's current send/recv sequence
+	// counters, for resyncing after a reconnect
+	MessageSyncSession = "syncSession"
+
+	// MessageGetSessionStatus - fetch the session's rolling status digest
+	MessageGetSessionStatus = "getSessionStatus"
+
+	// MessageHelloClient - required handshake sent before hostSession or
+	// joinSession; negotiates the protocol version for the connection
+	MessageHelloClient = "helloClient"
 )
 
+// defaultMaxSessionTTL - the default upper bound on how far into the future
+// extendSession may push a session's expiry
+const defaultMaxSessionTTL = 7 * 24 * time.Hour
+
+// proposalTTL - how long a session proposal remains approvable
+const proposalTTL = 5 * time.Minute
+
+// errOutOfOrder - returned (non-fatally) when a relayed message's Seq skips
+// ahead of what the server expects, so the client knows to resync
+const errOutOfOrder = "out of order"
+
 // MessageHandler - handles rpc messages
 type MessageHandler struct {
 	session *models.Session
+	isHost  bool
+
+	// hostedSessionID - the session id this connection registered as host
+	// for via hostSession, set regardless of whether a Session has been
+	// approved yet. Authorizes approveSession/rejectSession: a host may
+	// only decide proposals for the id it's actually listening on
+	hostedSessionID string
+
+	maxSessionTTL time.Duration
+
+	// helloReceived/protocolMajor/protocolMinor - the negotiated protocol
+	// version for this connection, set by handleHelloClient. Handlers that
+	// add fields not understood by older SDKs should gate them behind
+	// protocolMinor via supportsMinor
+	helloReceived bool
+	protocolMajor int
+	protocolMinor int
 
 	sendCh chan<- interface{}
 	subCh  chan interface{}
 
-	store  store.Store
-	pubSub *PubSub
+	store   store.Store
+	codable *store.CodableStore
+	pubSub  *PubSub
+
+	// OnProposal - optional hook invoked whenever a guest's proposeSession
+	// is persisted, for observability
+	OnProposal func(*models.SessionProposal)
+
+	// OnApprove - optional hook invoked whenever a host's approveSession
+	// results in a persisted Session, for observability
+	OnApprove func(*models.Session)
 }
 
 // NewMessageHandler - construct a MessageHandler
@@ -45,26 +123,68 @@ func NewMessageHandler(
 		return nil, errors.Errorf("pubSub must not be nil")
 	}
 	return &MessageHandler{
-		sendCh: sendCh,
-		subCh:  make(chan interface{}),
-		store:  sto,
-		pubSub: pubSub,
+		sendCh:        sendCh,
+		subCh:         make(chan interface{}),
+		store:         sto,
+		codable:       store.NewCodableStore(sto),
+		pubSub:        pubSub,
+		maxSessionTTL: defaultMaxSessionTTL,
 	}, nil
 }
 
+// SetMaxSessionTTL - override the maximum TTL extendSession will grant for
+// this connection. A non-positive value is ignored
+func (c *MessageHandler) SetMaxSessionTTL(d time.Duration) {
+	if d > 0 {
+		c.maxSessionTTL = d
+	}
+}
+
 // Handle - handle an RPC message
 func (c *MessageHandler) Handle(req *Request) (ok bool) {
 	var res *Response
 
 	if req.ID < 1 {
 		res = errorResponse(req.ID, "invalid request ID", true)
+	} else if req.Message != MessageHelloClient && !c.helloReceived {
+		res = errorResponse(req.ID, "handshake required", true)
 	} else {
 		switch req.Message {
+		case MessageHelloClient:
+			res = c.handleHelloClient(req.ID, req.Data)
+
 		case HostMessageHostSession:
 			res = c.handleHostSession(req.ID, req.Data)
 
 		case GuestMessageJoinSession:
 			res = c.handleJoinSession(req.ID, req.Data)
+
+		case HostMessageUpdateSession:
+			res = c.handleUpdateSession(req.ID, req.Data)
+
+		case HostMessageExtendSession:
+			res = c.handleExtendSession(req.ID, req.Data)
+
+		case MessagePingSession:
+			res = c.handlePingSession(req.ID)
+
+		case GuestMessageProposeSession:
+			res = c.handleProposeSession(req.ID, req.Data)
+
+		case HostMessageApproveSession:
+			res = c.handleApproveSession(req.ID, req.Data)
+
+		case HostMessageRejectSession:
+			res = c.handleRejectSession(req.ID, req.Data)
+
+		case MessagePublish:
+			res = c.handlePublish(req)
+
+		case MessageSyncSession:
+			res = c.handleSyncSession(req.ID, req.Data)
+
+		case MessageGetSessionStatus:
+			res = c.handleGetSessionStatus(req.ID)
 		}
 	}
 
@@ -83,6 +203,48 @@ func (c *MessageHandler) Close() {
 	close(c.subCh)
 }
 
+// handleHelloClient - negotiates the RPC protocol version for this
+// connection. A client whose major version differs from the server's is
+// rejected outright; a differing minor version is fine, since minor bumps
+// only ever add fields, never remove or repurpose them
+func (c *MessageHandler) handleHelloClient(
+	requestID int,
+	data map[string]string,
+) *Response {
+	major, err := strconv.Atoi(data["protocolMajor"])
+	if err != nil {
+		return errorResponse(requestID, "invalid protocolMajor", true)
+	}
+	minor, err := strconv.Atoi(data["protocolMinor"])
+	if err != nil {
+		return errorResponse(requestID, "invalid protocolMinor", true)
+	}
+
+	if major != ServerProtocolMajor {
+		return errorResponse(requestID, "unsupported protocol", true)
+	}
+
+	c.helloReceived = true
+	c.protocolMajor = major
+	c.protocolMinor = minor
+
+	return &Response{
+		RequestID: requestID,
+		Data: map[string]string{
+			"serverProtocolMajor": strconv.Itoa(ServerProtocolMajor),
+			"serverProtocolMinor": strconv.Itoa(ServerProtocolMinor),
+			"serverProtocolPatch": strconv.Itoa(ServerProtocolPatch),
+		},
+	}
+}
+
+// supportsMinor - whether the negotiated client protocol version is at
+// least minor, for gating newer optional fields behind a minor-version
+// check so older SDKs keep working unchanged
+func (c *MessageHandler) supportsMinor(minor int) bool {
+	return c.protocolMinor >= minor
+}
+
 func (c *MessageHandler) handleHostSession(
 	requestID int,
 	data map[string]string,
@@ -94,16 +256,18 @@ func (c *MessageHandler) handleHostSession(
 		return res
 	}
 
-	if session == nil {
-		// there isn't an existing session; persist the new session
-		session = &models.Session{ID: sessionID, Key: sessionKey}
-		if err := c.store.Set(session.StoreKey(), session); err != nil {
-			fmt.Println(errors.Wrap(err, "failed to persist session"))
-			return errorResponse(requestID, "internal error", true)
-		}
+	// unlike before, there's no eager persistence of a new Session here: a
+	// Session only comes into existence once a guest's proposeSession has
+	// been approved via approveSession. Until then, the host is simply
+	// listening on its pub/sub topic for proposals. An expired session is
+	// treated the same as no session at all, so the host ends up waiting
+	// for a fresh proposal rather than resuming a dead one
+	if session != nil && isSessionLive(session) {
+		c.session = session
 	}
 
-	c.session = session
+	c.isHost = true
+	c.hostedSessionID = sessionID
 	c.pubSub.Subscribe(hostPubSubID(sessionID), c.subCh)
 
 	return &Response{RequestID: requestID}
@@ -120,18 +284,491 @@ func (c *MessageHandler) handleJoinSession(
 		return res
 	}
 
-	if session == nil {
-		// there isn't an existing session; fail
+	if session == nil || !isSessionLive(session) {
+		// there isn't an existing, unexpired session; fail
 		errMsg := fmt.Sprintf("no such session: %s", sessionID)
 		return errorResponse(requestID, errMsg, false)
 	}
 
+	// a session's Accounts/RequiredNamespaces are only ever set via the
+	// proposeSession -> approveSession flow; joinSession just attaches this
+	// connection to the session that flow already established
 	c.session = session
+	c.isHost = false
+	c.pubSub.Subscribe(guestPubSubID(sessionID), c.subCh)
+
+	return &Response{RequestID: requestID}
+}
+
+// handleUpdateSession - lets the controlling host mutate a subset of the
+// session's metadata (accounts, chain hints). Guests are not permitted to
+// call this; doing so is rejected rather than silently ignored so a
+// misbehaving client finds out immediately
+func (c *MessageHandler) handleUpdateSession(
+	requestID int,
+	data map[string]string,
+) *Response {
+	if c.session == nil {
+		return errorResponse(requestID, "no active session", true)
+	}
+	if !c.isHost {
+		return errorResponse(requestID, "only the host may update the session", true)
+	}
+
+	if accounts, ok := data["accounts"]; ok {
+		approved := splitNonEmpty(accounts)
+		if err := caip.ValidateAccountIDs(approved); err != nil {
+			return caipErrorResponse(requestID, err, true)
+		}
+		if err := caip.CoversRequiredNamespaces(c.session.RequiredNamespaces, approved); err != nil {
+			return caipErrorResponse(requestID, err, true)
+		}
+		c.session.Accounts = approved
+	}
+	if chainHints, ok := data["chainHints"]; ok {
+		c.session.ChainHints = splitNonEmpty(chainHints)
+	}
+
+	if err := c.store.Set(c.session.StoreKey(), c.session); err != nil {
+		fmt.Println(errors.Wrap(err, "failed to persist session"))
+		return errorResponse(requestID, "internal error", true)
+	}
+
+	c.publish(c.session.ID, guestPubSubID(c.session.ID), &Response{
+		RequestID: requestID,
+		Data:      data,
+	})
+
+	return &Response{RequestID: requestID}
+}
+
+// handleExtendSession - bumps the session's expiry, rejecting requests that
+// ask for an expiry further out than maxSessionTTL allows
+func (c *MessageHandler) handleExtendSession(
+	requestID int,
+	data map[string]string,
+) *Response {
+	if c.session == nil {
+		return errorResponse(requestID, "no active session", true)
+	}
+	if !c.isHost {
+		return errorResponse(requestID, "only the host may extend the session", true)
+	}
+
+	ttlSeconds, err := strconv.ParseInt(data["ttl"], 10, 64)
+	if err != nil || ttlSeconds <= 0 {
+		return errorResponse(requestID, "invalid ttl", true)
+	}
+
+	ttl := time.Duration(ttlSeconds) * time.Second
+	if ttl > c.maxSessionTTL {
+		return errorResponse(requestID, "requested ttl exceeds maximum", true)
+	}
+
+	c.session.ExpiresAt = time.Now().Add(ttl).Unix()
+	if err := c.store.Set(c.session.StoreKey(), c.session); err != nil {
+		fmt.Println(errors.Wrap(err, "failed to persist session"))
+		return errorResponse(requestID, "internal error", true)
+	}
+
+	c.publish(c.session.ID, guestPubSubID(c.session.ID), &Response{
+		RequestID: requestID,
+		Data:      map[string]string{"expiresAt": strconv.FormatInt(c.session.ExpiresAt, 10)},
+	})
+
+	return &Response{RequestID: requestID}
+}
+
+// handlePingSession - a cheap keep-alive; succeeds only if both the host and
+// guest are currently subscribed to the session's pub/sub topics
+func (c *MessageHandler) handlePingSession(requestID int) *Response {
+	if c.session == nil {
+		return errorResponse(requestID, "no active session", true)
+	}
+	if !isSessionLive(c.session) {
+		return errorResponse(requestID, "session expired", false)
+	}
+
+	hostUp := c.pubSub.IsSubscribed(hostPubSubID(c.session.ID))
+	guestUp := c.pubSub.IsSubscribed(guestPubSubID(c.session.ID))
+	if !hostUp || !guestUp {
+		return errorResponse(requestID, "not connected", false)
+	}
+
+	return &Response{RequestID: requestID}
+}
+
+// handleProposeSession - a guest's first message when pairing with a host
+// it hasn't established a session with yet. Stores the proposal pending the
+// host's approveSession/rejectSession and notifies the host over pub/sub;
+// the guest itself subscribes so it can observe that decision
+func (c *MessageHandler) handleProposeSession(
+	requestID int,
+	data map[string]string,
+) *Response {
+	sessionID, sessionKey, proposalID := data["id"], data["key"], data["proposalId"]
+
+	if !models.IsValidSessionID(sessionID) {
+		return errorResponse(requestID, "invalid session id", true)
+	}
+	if !models.IsValidSessionKey(sessionKey) {
+		return errorResponse(requestID, "invalid session key", true)
+	}
+	if proposalID == "" {
+		return errorResponse(requestID, "proposalId is required", true)
+	}
+
+	existing := &models.Session{ID: sessionID}
+	exists, err := c.store.Get(existing.StoreKey(), existing)
+	if err != nil {
+		fmt.Println(errors.Wrap(err, "failed to load session"))
+		return errorResponse(requestID, "internal error", true)
+	}
+	if exists && isSessionLive(existing) {
+		errMsg := fmt.Sprintf("session already established: %s", sessionID)
+		return errorResponse(requestID, errMsg, true)
+	}
+
+	var namespaces map[string]caip.Namespace
+	if raw := data["requiredNamespaces"]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &namespaces); err != nil {
+			return errorResponseCode(requestID, "malformed requiredNamespaces", string(caip.ErrInvalidFormat), true)
+		}
+		for _, ns := range namespaces {
+			for _, chainID := range ns.Chains {
+				if err := caip.ValidateChainID(chainID); err != nil {
+					return caipErrorResponse(requestID, err, true)
+				}
+			}
+		}
+	}
+
+	proposal := &models.SessionProposal{
+		ID:                 proposalID,
+		SessionID:          sessionID,
+		SessionKey:         sessionKey,
+		RequiredNamespaces: namespaces,
+		ExpiresAt:          time.Now().Add(proposalTTL).Unix(),
+	}
+	if err := c.codable.Set(proposal); err != nil {
+		fmt.Println(errors.Wrap(err, "failed to persist session proposal"))
+		return errorResponse(requestID, "internal error", true)
+	}
+
+	c.isHost = false
 	c.pubSub.Subscribe(guestPubSubID(sessionID), c.subCh)
+	c.publish(sessionID, hostPubSubID(sessionID), &Response{
+		RequestID: requestID,
+		Data: map[string]string{
+			"proposalId":         proposalID,
+			"requiredNamespaces": data["requiredNamespaces"],
+		},
+	})
+
+	if c.OnProposal != nil {
+		c.OnProposal(proposal)
+	}
 
 	return &Response{RequestID: requestID}
 }
 
+// handleApproveSession - the host's acceptance of a pending proposal. Only
+// now is a models.Session actually written
+func (c *MessageHandler) handleApproveSession(
+	requestID int,
+	data map[string]string,
+) *Response {
+	if !c.isHost {
+		return errorResponse(requestID, "only the host may approve a session", true)
+	}
+
+	proposal := &models.SessionProposal{ID: data["proposalId"]}
+	ok, err := c.codable.Get(proposal)
+	if err != nil {
+		fmt.Println(errors.Wrap(err, "failed to load session proposal"))
+		return errorResponse(requestID, "internal error", true)
+	}
+	if !ok {
+		return errorResponse(requestID, "no such proposal", true)
+	}
+	// a host may only decide proposals addressed to the session id it's
+	// actually registered as host for, not any proposal on the server
+	if proposal.SessionID != c.hostedSessionID {
+		return errorResponse(requestID, "not the host of this session", true)
+	}
+	if proposal.ExpiresAt < time.Now().Unix() {
+		return errorResponse(requestID, "proposal expired", true)
+	}
+
+	accounts := splitNonEmpty(data["accounts"])
+	if err := caip.ValidateAccountIDs(accounts); err != nil {
+		return caipErrorResponse(requestID, err, true)
+	}
+	if err := caip.CoversRequiredNamespaces(proposal.RequiredNamespaces, accounts); err != nil {
+		return caipErrorResponse(requestID, err, true)
+	}
+
+	// the session key is the one the guest chose in proposeSession, not
+	// anything the host supplies here - the host never learns the guest's
+	// key out of band, and the guest has no way to learn a key the host
+	// invented at approval time
+	session := &models.Session{
+		ID:                 proposal.SessionID,
+		Key:                proposal.SessionKey,
+		Accounts:           accounts,
+		RequiredNamespaces: proposal.RequiredNamespaces,
+	}
+	if err := c.store.Set(session.StoreKey(), session); err != nil {
+		fmt.Println(errors.Wrap(err, "failed to persist session"))
+		return errorResponse(requestID, "internal error", true)
+	}
+
+	// the proposal is now decided; delete it so a later approveSession or
+	// rejectSession for the same id can't replay the decision
+	if err := c.codable.Delete(proposal); err != nil {
+		fmt.Println(errors.Wrap(err, "failed to delete session proposal"))
+		return errorResponse(requestID, "internal error", true)
+	}
+
+	c.session = session
+
+	// the guest already knows proposal.SessionKey (it chose it), so it can
+	// simply call joinSession once it observes this broadcast to finish
+	// establishing its own connection's session state
+	c.publish(session.ID, guestPubSubID(session.ID), &Response{
+		RequestID: requestID,
+		Data: map[string]string{
+			"proposalId": proposal.ID,
+			"accounts":   data["accounts"],
+		},
+	})
+
+	if c.OnApprove != nil {
+		c.OnApprove(session)
+	}
+
+	return &Response{RequestID: requestID}
+}
+
+// handleRejectSession - the host's rejection of a pending proposal
+func (c *MessageHandler) handleRejectSession(
+	requestID int,
+	data map[string]string,
+) *Response {
+	if !c.isHost {
+		return errorResponse(requestID, "only the host may reject a session", true)
+	}
+
+	proposal := &models.SessionProposal{ID: data["proposalId"]}
+	ok, err := c.codable.Get(proposal)
+	if err != nil {
+		fmt.Println(errors.Wrap(err, "failed to load session proposal"))
+		return errorResponse(requestID, "internal error", true)
+	}
+	if !ok {
+		return errorResponse(requestID, "no such proposal", true)
+	}
+	if proposal.SessionID != c.hostedSessionID {
+		return errorResponse(requestID, "not the host of this session", true)
+	}
+
+	if err := c.codable.Delete(proposal); err != nil {
+		fmt.Println(errors.Wrap(err, "failed to delete session proposal"))
+		return errorResponse(requestID, "internal error", true)
+	}
+
+	c.publish(proposal.SessionID, guestPubSubID(proposal.SessionID), &Response{
+		RequestID: requestID,
+		Error:     data["reason"],
+		Data:      map[string]string{"proposalId": proposal.ID},
+	})
+
+	return &Response{RequestID: requestID}
+}
+
+// handlePublish - relays req's payload to the other side of the session.
+// req.Seq must be exactly one past the last sequence number accepted from
+// this sender: gaps are rejected as out of order so the client can resync,
+// and values at or below the last accepted one are duplicates and are
+// dropped silently (but still acked, since the client already saw them
+// succeed once)
+func (c *MessageHandler) handlePublish(req *Request) *Response {
+	requestID := req.ID
+
+	if c.session == nil {
+		return errorResponse(requestID, "no active session", true)
+	}
+	if !isSessionLive(c.session) {
+		return errorResponse(requestID, "session expired", false)
+	}
+
+	seqKey, destTopic := c.relayDirection()
+
+	var expected uint64
+	if _, err := c.store.Get(seqKey, &expected); err != nil {
+		fmt.Println(errors.Wrap(err, "failed to load sequence counter"))
+		return errorResponse(requestID, "internal error", true)
+	}
+
+	if req.Seq <= expected {
+		return &Response{RequestID: requestID}
+	}
+	if req.Seq != expected+1 {
+		return errorResponse(requestID, errOutOfOrder, false)
+	}
+
+	if err := c.store.Set(seqKey, req.Seq); err != nil {
+		fmt.Println(errors.Wrap(err, "failed to persist sequence counter"))
+		return errorResponse(requestID, "internal error", true)
+	}
+
+	buf := &models.ReplayBuffer{SessionID: c.session.ID}
+	if _, err := c.codable.Get(buf); err != nil {
+		fmt.Println(errors.Wrap(err, "failed to load replay buffer"))
+		return errorResponse(requestID, "internal error", true)
+	}
+	buf.Append(models.RelayedMessage{Seq: req.Seq, Topic: destTopic, Data: req.Data})
+	if err := c.codable.Set(buf); err != nil {
+		fmt.Println(errors.Wrap(err, "failed to persist replay buffer"))
+		return errorResponse(requestID, "internal error", true)
+	}
+
+	c.publish(c.session.ID, destTopic, &Response{
+		RequestID: requestID,
+		Data:      req.Data,
+	})
+
+	return &Response{RequestID: requestID}
+}
+
+// handleSyncSession - returns the session's current send/recv sequence
+// counters, plus any buffered messages addressed to this connection with a
+// sequence number greater than the sinceSeq it supplies, so a reconnecting
+// client can tell which relayed messages it missed and re-request them
+// without a full resync
+func (c *MessageHandler) handleSyncSession(requestID int, data map[string]string) *Response {
+	if c.session == nil {
+		return errorResponse(requestID, "no active session", true)
+	}
+
+	var hostSeq, guestSeq uint64
+	if _, err := c.store.Get(seqStoreKeyHostToGuest(c.session.ID), &hostSeq); err != nil {
+		fmt.Println(errors.Wrap(err, "failed to load sequence counter"))
+		return errorResponse(requestID, "internal error", true)
+	}
+	if _, err := c.store.Get(seqStoreKeyGuestToHost(c.session.ID), &guestSeq); err != nil {
+		fmt.Println(errors.Wrap(err, "failed to load sequence counter"))
+		return errorResponse(requestID, "internal error", true)
+	}
+
+	var sinceSeq uint64
+	if raw, ok := data["sinceSeq"]; ok && raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return errorResponse(requestID, "invalid sinceSeq", true)
+		}
+		sinceSeq = parsed
+	}
+
+	// messages "addressed to this connection" are the ones relayed to the
+	// topic it's subscribed on: the guest's topic for a guest, the host's
+	// for a host
+	destTopic := guestPubSubID(c.session.ID)
+	if c.isHost {
+		destTopic = hostPubSubID(c.session.ID)
+	}
+
+	buf := &models.ReplayBuffer{SessionID: c.session.ID}
+	if _, err := c.codable.Get(buf); err != nil {
+		fmt.Println(errors.Wrap(err, "failed to load replay buffer"))
+		return errorResponse(requestID, "internal error", true)
+	}
+
+	var missed []models.RelayedMessage
+	for _, msg := range buf.Messages {
+		if msg.Topic == destTopic && msg.Seq > sinceSeq {
+			missed = append(missed, msg)
+		}
+	}
+	missedJSON, err := json.Marshal(missed)
+	if err != nil {
+		fmt.Println(errors.Wrap(err, "failed to marshal missed messages"))
+		return errorResponse(requestID, "internal error", true)
+	}
+
+	return &Response{
+		RequestID: requestID,
+		Data: map[string]string{
+			"hostSeq":  strconv.FormatUint(hostSeq, 10),
+			"guestSeq": strconv.FormatUint(guestSeq, 10),
+			"missed":   string(missedJSON),
+		},
+	}
+}
+
+// relayDirection - the sequence counter key and destination pub/sub topic
+// for a publish sent by the current connection
+func (c *MessageHandler) relayDirection() (seqKey string, destTopic string) {
+	if c.isHost {
+		return seqStoreKeyHostToGuest(c.session.ID), guestPubSubID(c.session.ID)
+	}
+	return seqStoreKeyGuestToHost(c.session.ID), hostPubSubID(c.session.ID)
+}
+
+// handleGetSessionStatus - returns the session's rolling status digest, so a
+// reconnecting client can tell at a glance (without replaying anything)
+// whether it's still caught up
+func (c *MessageHandler) handleGetSessionStatus(requestID int) *Response {
+	if c.session == nil {
+		return errorResponse(requestID, "no active session", true)
+	}
+
+	digest := &models.StatusDigest{SessionID: c.session.ID}
+	if _, err := c.codable.Get(digest); err != nil {
+		fmt.Println(errors.Wrap(err, "failed to load status digest"))
+		return errorResponse(requestID, "internal error", true)
+	}
+
+	return &Response{
+		RequestID: requestID,
+		Data: map[string]string{
+			"sessionID": c.session.ID,
+			"digest":    hex.EncodeToString(digest.Digest),
+			"count":     strconv.FormatUint(digest.Count, 10),
+		},
+	}
+}
+
+// publish - broadcasts res on topic and folds it into sessionID's rolling
+// status digest (sha256(prev_digest || event_bytes)), so getSessionStatus
+// can offer a cheap O(32 byte) way to detect whether a client has missed
+// anything since it last checked in
+func (c *MessageHandler) publish(sessionID string, topic string, res *Response) {
+	c.pubSub.Publish(topic, res)
+
+	eventBytes, err := json.Marshal(res)
+	if err != nil {
+		fmt.Println(errors.Wrap(err, "failed to marshal event for status digest"))
+		return
+	}
+
+	digest := &models.StatusDigest{SessionID: sessionID}
+	if _, err := c.codable.Get(digest); err != nil {
+		fmt.Println(errors.Wrap(err, "failed to load status digest"))
+		return
+	}
+
+	h := sha256.New()
+	h.Write(digest.Digest)
+	h.Write(eventBytes)
+	digest.Digest = h.Sum(nil)
+	digest.Count++
+
+	if err := c.codable.Set(digest); err != nil {
+		fmt.Println(errors.Wrap(err, "failed to persist status digest"))
+	}
+}
+
 func (c *MessageHandler) findSession(
 	requestID int,
 	sessionID string,
@@ -171,6 +808,31 @@ func errorResponse(requestID int, errorMessage string, fatal bool) *Response {
 	}
 }
 
+func errorResponseCode(requestID int, errorMessage string, errorCode string, fatal bool) *Response {
+	res := errorResponse(requestID, errorMessage, fatal)
+	res.ErrorCode = errorCode
+	return res
+}
+
+// caipErrorResponse - builds a Response from a caip validation error,
+// preserving its structured error code when present
+func caipErrorResponse(requestID int, err error, fatal bool) *Response {
+	if caipErr, ok := err.(*caip.Error); ok {
+		return errorResponseCode(requestID, caipErr.Message, string(caipErr.Code), fatal)
+	}
+	return errorResponse(requestID, err.Error(), fatal)
+}
+
+func splitNonEmpty(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
 func hostPubSubID(sessionID string) string {
 	return "h." + sessionID
 }
@@ -178,3 +840,17 @@ func hostPubSubID(sessionID string) string {
 func guestPubSubID(sessionID string) string {
 	return "g." + sessionID
 }
+
+func seqStoreKeyHostToGuest(sessionID string) string {
+	return "seq/h/" + sessionID
+}
+
+func seqStoreKeyGuestToHost(sessionID string) string {
+	return "seq/g/" + sessionID
+}
+
+// isSessionLive - whether session is still within its TTL. A zero
+// ExpiresAt means extendSession was never called, which is not an expiry
+func isSessionLive(session *models.Session) bool {
+	return session.ExpiresAt == 0 || session.ExpiresAt > time.Now().Unix()
+}
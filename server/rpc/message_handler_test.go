@@ -0,0 +1,171 @@
+// Copyright (c) 2019 Coinbase, Inc. See LICENSE
+
+package rpc
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/CoinbaseWallet/walletlinkd/store"
+)
+
+// memStore - a minimal in-process store.Store for tests
+type memStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newMemStore() *memStore {
+	return &memStore{data: map[string][]byte{}}
+}
+
+func (m *memStore) Get(key string, dest interface{}) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	raw, ok := m.data[key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, dest)
+}
+
+func (m *memStore) Set(key string, val interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	raw, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	m.data[key] = raw
+	return nil
+}
+
+func (m *memStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, key)
+	return nil
+}
+
+var _ store.Store = (*memStore)(nil)
+
+func newTestHandler(t *testing.T, sto store.Store, pubSub *PubSub) (*MessageHandler, chan interface{}) {
+	t.Helper()
+
+	sendCh := make(chan interface{}, 16)
+	handler, err := NewMessageHandler(sendCh, sto, pubSub)
+	if err != nil {
+		t.Fatalf("NewMessageHandler: %v", err)
+	}
+
+	// a handler's subCh is where it receives pub/sub broadcasts (session
+	// approvals, relayed publishes, etc.); nothing in these tests asserts on
+	// that traffic, but it must still be drained or a Publish blocked on an
+	// unbuffered subCh would hang
+	go func() {
+		for range handler.subCh {
+		}
+	}()
+
+	if ok := handler.Handle(&Request{ID: 1, Message: MessageHelloClient, Data: map[string]string{
+		"protocolMajor": strconv.Itoa(ServerProtocolMajor),
+		"protocolMinor": "0",
+	}}); !ok {
+		t.Fatalf("handshake failed")
+	}
+	res := (<-sendCh).(*Response)
+	if res.Error != "" {
+		t.Fatalf("handshake failed: %s", res.Error)
+	}
+
+	return handler, sendCh
+}
+
+// TestProposeApproveSession exercises the full propose -> approve happy path
+// and the authorization bug fixed in review: a connection hosting a
+// different session id must not be able to approve or re-decide someone
+// else's proposal.
+func TestProposeApproveSession(t *testing.T) {
+	sto := newMemStore()
+	pubSub := NewPubSub()
+
+	host, _ := newTestHandler(t, sto, pubSub)
+	if ok := host.Handle(&Request{ID: 1, Message: HostMessageHostSession, Data: map[string]string{
+		"id": "session1", "key": "hostkey1",
+	}}); !ok {
+		t.Fatalf("hostSession failed")
+	}
+
+	otherHost, _ := newTestHandler(t, sto, pubSub)
+	if ok := otherHost.Handle(&Request{ID: 1, Message: HostMessageHostSession, Data: map[string]string{
+		"id": "session2", "key": "hostkey2",
+	}}); !ok {
+		t.Fatalf("hostSession failed")
+	}
+
+	guest, _ := newTestHandler(t, sto, pubSub)
+	if ok := guest.Handle(&Request{ID: 2, Message: GuestMessageProposeSession, Data: map[string]string{
+		"id":                 "session1",
+		"key":                "guestkey1",
+		"proposalId":         "p1",
+		"requiredNamespaces": `{"eip155":{"Chains":["eip155:1"],"Methods":["eth_sign"]}}`,
+	}}); !ok {
+		t.Fatalf("proposeSession failed")
+	}
+
+	// a host registered for a *different* session id must not be able to
+	// approve this proposal
+	if ok := otherHost.Handle(&Request{ID: 2, Message: HostMessageApproveSession, Data: map[string]string{
+		"proposalId": "p1",
+		"accounts":   "eip155:1:0xabc",
+	}}); ok {
+		t.Fatalf("expected approveSession from the wrong host to fail")
+	}
+
+	if ok := host.Handle(&Request{ID: 3, Message: HostMessageApproveSession, Data: map[string]string{
+		"proposalId": "p1",
+		"accounts":   "eip155:1:0xabc",
+	}}); !ok {
+		t.Fatalf("approveSession failed")
+	}
+
+	session := &struct {
+		ID  string
+		Key string
+	}{}
+	ok, err := sto.Get("session:session1", session)
+	if err != nil || !ok {
+		t.Fatalf("expected session1 to be persisted: ok=%v err=%v", ok, err)
+	}
+	if session.Key != "guestkey1" {
+		t.Fatalf("expected persisted session key to be the guest's proposed key, got %q", session.Key)
+	}
+
+	// the proposal must be consumed: neither a duplicate approve nor a
+	// late reject should still find it
+	if ok := host.Handle(&Request{ID: 4, Message: HostMessageApproveSession, Data: map[string]string{
+		"proposalId": "p1",
+		"accounts":   "eip155:1:0xabc",
+	}}); ok {
+		t.Fatalf("expected re-approving a decided proposal to fail")
+	}
+	if ok := host.Handle(&Request{ID: 5, Message: HostMessageRejectSession, Data: map[string]string{
+		"proposalId": "p1",
+		"reason":     "too late",
+	}}); ok {
+		t.Fatalf("expected rejecting a decided proposal to fail")
+	}
+
+	// a fresh proposal for the now-live session must be rejected rather
+	// than allowed to clobber the approved session on a later approval
+	guest2, _ := newTestHandler(t, sto, pubSub)
+	if ok := guest2.Handle(&Request{ID: 5, Message: GuestMessageProposeSession, Data: map[string]string{
+		"id":         "session1",
+		"key":        "guestkey2",
+		"proposalId": "p2",
+	}}); ok {
+		t.Fatalf("expected proposeSession against a live session to fail")
+	}
+}
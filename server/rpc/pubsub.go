@@ -0,0 +1,68 @@
+// Copyright (c) 2019 Coinbase, Inc. See LICENSE
+
+package rpc
+
+import "sync"
+
+// PubSub - topic-based publish/subscribe hub used to relay messages
+// between a session's host and guest connections
+type PubSub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan interface{}]bool
+}
+
+// NewPubSub - construct a PubSub
+func NewPubSub() *PubSub {
+	return &PubSub{subs: map[string]map[chan interface{}]bool{}}
+}
+
+// Subscribe - subscribe ch to topic
+func (p *PubSub) Subscribe(topic string, ch chan interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.subs[topic] == nil {
+		p.subs[topic] = map[chan interface{}]bool{}
+	}
+	p.subs[topic][ch] = true
+}
+
+// Unsubscribe - unsubscribe ch from topic
+func (p *PubSub) Unsubscribe(topic string, ch chan interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.subs[topic], ch)
+}
+
+// UnsubscribeAll - unsubscribe ch from every topic it's subscribed to
+func (p *PubSub) UnsubscribeAll(ch chan interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for topic := range p.subs {
+		delete(p.subs[topic], ch)
+	}
+}
+
+// IsSubscribed - whether topic has at least one subscriber
+func (p *PubSub) IsSubscribed(topic string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.subs[topic]) > 0
+}
+
+// Publish - broadcast msg to every subscriber of topic. The subscriber set
+// is copied out while the lock is held, and the (potentially blocking)
+// sends happen after it's released, so one subscriber that isn't draining
+// its channel can't freeze every other topic's Publish/Subscribe/Unsubscribe
+// call along with it
+func (p *PubSub) Publish(topic string, msg interface{}) {
+	p.mu.Lock()
+	subs := make([]chan interface{}, 0, len(p.subs[topic]))
+	for ch := range p.subs[topic] {
+		subs = append(subs, ch)
+	}
+	p.mu.Unlock()
+
+	for _, ch := range subs {
+		ch <- msg
+	}
+}
@@ -0,0 +1,14 @@
+// Copyright (c) 2019 Coinbase, Inc. See LICENSE
+
+package rpc
+
+// Request - an incoming RPC request
+type Request struct {
+	ID      int
+	Message string
+	Data    map[string]string
+
+	// Seq - the sender's monotonic sequence number for this message,
+	// required on relayed messages (e.g. publish) and ignored otherwise
+	Seq uint64
+}
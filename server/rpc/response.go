@@ -0,0 +1,14 @@
+// Copyright (c) 2019 Coinbase, Inc. See LICENSE
+
+package rpc
+
+// Response - an outgoing RPC response
+type Response struct {
+	RequestID int
+	Data      map[string]string
+	Error     string
+	// ErrorCode - a machine-readable error code, e.g. from the caip package.
+	// Empty when Error is empty or the failure has no structured code
+	ErrorCode string
+	Fatal     bool
+}
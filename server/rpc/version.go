@@ -0,0 +1,13 @@
+// Copyright (c) 2019 Coinbase, Inc. See LICENSE
+
+package rpc
+
+// ServerProtocolMajor/Minor/Patch - this server's semver-style RPC protocol
+// version. Clients negotiate against these via helloClient; a differing
+// major version is rejected outright, while minor gates let message
+// handlers add optional fields without breaking older SDKs
+const (
+	ServerProtocolMajor = 1
+	ServerProtocolMinor = 0
+	ServerProtocolPatch = 0
+)
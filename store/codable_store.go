@@ -0,0 +1,36 @@
+// Copyright (c) 2019 Coinbase, Inc. See LICENSE
+
+package store
+
+// Codable - a value that knows the key it's persisted under
+type Codable interface {
+	StoreKey() string
+}
+
+// CodableStore - a typed wrapper around a Store. Rather than separately
+// tracking a key and an untyped payload, callers pass a Codable whose
+// StoreKey() determines where it lives, so payloads round-trip through a
+// real schema instead of an ad hoc map[string]string
+type CodableStore struct {
+	store Store
+}
+
+// NewCodableStore - construct a CodableStore backed by sto
+func NewCodableStore(sto Store) *CodableStore {
+	return &CodableStore{store: sto}
+}
+
+// Get - load the persisted value for dest's StoreKey into dest
+func (c *CodableStore) Get(dest Codable) (bool, error) {
+	return c.store.Get(dest.StoreKey(), dest)
+}
+
+// Set - persist val under its own StoreKey
+func (c *CodableStore) Set(val Codable) error {
+	return c.store.Set(val.StoreKey(), val)
+}
+
+// Delete - remove val's persisted value
+func (c *CodableStore) Delete(val Codable) error {
+	return c.store.Delete(val.StoreKey())
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2019 Coinbase, Inc. See LICENSE
+
+package models
+
+// ReplayBufferCapacity - the maximum number of relayed messages retained per
+// session for resync purposes
+const ReplayBufferCapacity = 32
+
+// RelayedMessage - a single message relayed between a session's host and
+// guest over pub/sub
+type RelayedMessage struct {
+	Seq   uint64
+	Topic string
+	Data  map[string]string
+}
+
+// ReplayBuffer - a bounded history of the most recently relayed messages for
+// a session, so a reconnecting client can re-request anything it missed
+type ReplayBuffer struct {
+	SessionID string
+	Messages  []RelayedMessage
+}
+
+// StoreKey - key used to persist this buffer in the Store
+func (b *ReplayBuffer) StoreKey() string {
+	return "replay:" + b.SessionID
+}
+
+// Append - appends msg, dropping the oldest entry once the buffer exceeds
+// ReplayBufferCapacity
+func (b *ReplayBuffer) Append(msg RelayedMessage) {
+	b.Messages = append(b.Messages, msg)
+	if len(b.Messages) > ReplayBufferCapacity {
+		b.Messages = b.Messages[len(b.Messages)-ReplayBufferCapacity:]
+	}
+}
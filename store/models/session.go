@@ -0,0 +1,46 @@
+// Copyright (c) 2019 Coinbase, Inc. See LICENSE
+
+package models
+
+import (
+	"regexp"
+
+	"github.com/CoinbaseWallet/walletlinkd/caip"
+)
+
+var sessionIDPattern = regexp.MustCompile(`^[a-zA-Z0-9]{1,64}$`)
+var sessionKeyPattern = regexp.MustCompile(`^[a-zA-Z0-9]{1,64}$`)
+
+// Session - a host<->guest walletlink session
+type Session struct {
+	ID  string
+	Key string
+
+	// Accounts - CAIP-10 account strings the host has approved for the session
+	Accounts []string
+
+	// ChainHints - chain identifiers the host has indicated support for
+	ChainHints []string
+
+	// RequiredNamespaces - the CAIP-2 namespaces (chains + methods) the guest
+	// requested in its proposeSession, copied over when the host approves
+	RequiredNamespaces map[string]caip.Namespace
+
+	// ExpiresAt - unix timestamp (seconds) after which the session is considered expired
+	ExpiresAt int64
+}
+
+// StoreKey - key used to persist this session in the Store
+func (s *Session) StoreKey() string {
+	return "session:" + s.ID
+}
+
+// IsValidSessionID - whether id is a well-formed session id
+func IsValidSessionID(id string) bool {
+	return sessionIDPattern.MatchString(id)
+}
+
+// IsValidSessionKey - whether key is a well-formed session key
+func IsValidSessionKey(key string) bool {
+	return sessionKeyPattern.MatchString(key)
+}
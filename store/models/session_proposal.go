@@ -0,0 +1,23 @@
+// Copyright (c) 2019 Coinbase, Inc. See LICENSE
+
+package models
+
+import "github.com/CoinbaseWallet/walletlinkd/caip"
+
+// SessionProposal - a guest's pending request to establish a session,
+// awaiting the host's approveSession/rejectSession decision
+type SessionProposal struct {
+	ID                 string
+	SessionID          string
+	SessionKey         string
+	RequiredNamespaces map[string]caip.Namespace
+
+	// ExpiresAt - unix timestamp (seconds) after which the proposal can no
+	// longer be approved
+	ExpiresAt int64
+}
+
+// StoreKey - key used to persist this proposal in the Store
+func (p *SessionProposal) StoreKey() string {
+	return "proposal:" + p.ID
+}
@@ -0,0 +1,17 @@
+// Copyright (c) 2019 Coinbase, Inc. See LICENSE
+
+package models
+
+// StatusDigest - a rolling digest over every message relayed for a session,
+// so a reconnecting client can cheaply tell whether it missed anything
+// without replaying the full history
+type StatusDigest struct {
+	SessionID string
+	Digest    []byte
+	Count     uint64
+}
+
+// StoreKey - key used to persist this digest in the Store
+func (d *StatusDigest) StoreKey() string {
+	return "status:" + d.SessionID
+}
@@ -0,0 +1,17 @@
+// Copyright (c) 2019 Coinbase, Inc. See LICENSE
+
+package store
+
+// Store - a generic key/value persistence layer used to durably store
+// session state across connections
+type Store interface {
+	// Get - load the value stored at key into dest. ok is false if no value
+	// is stored at key
+	Get(key string, dest interface{}) (ok bool, err error)
+
+	// Set - persist val at key
+	Set(key string, val interface{}) error
+
+	// Delete - remove any value stored at key
+	Delete(key string) error
+}